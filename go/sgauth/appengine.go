@@ -0,0 +1,32 @@
+// +build !appengine
+
+//
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package sgauth
+
+import (
+	"context"
+	"time"
+)
+
+// appengineTokenFunc and appengineAppIDFunc, when non-nil, fetch tokens
+// and the app ID through the classic App Engine standard environment API.
+// Nothing in this package assigns them, so in this build both are nil.
+var appengineTokenFunc func(c context.Context, scopes ...string) (token string, expiry time.Time, err error)
+var appengineAppIDFunc func(c context.Context) string
+
+// appengineFlex is true when running on the App Engine flexible
+// environment, where credentials are discovered like on GCE instead.
+const appengineFlex = false
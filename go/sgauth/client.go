@@ -0,0 +1,99 @@
+//
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package sgauth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/google/oauth2l/go/sgauth/credentials"
+	"github.com/google/oauth2l/go/sgauth/internal"
+)
+
+// DefaultClient returns an *http.Client whose requests are authorized with
+// Application Default Credentials restricted to scope. It is a shortcut
+// for NewClient(ctx, &Settings{Scope: strings.Join(scope, " ")}).
+func DefaultClient(ctx context.Context, scope ...string) (*http.Client, error) {
+	return NewClient(ctx, &Settings{Scope: strings.Join(scope, " ")})
+}
+
+// NewClient returns an *http.Client whose requests carry an
+// "Authorization: Bearer" header sourced from settings, and, when
+// settings.QuotaProject is set, an "X-Goog-User-Project" header. If
+// settings has no Scope but does have an Audience, and the discovered
+// credentials are a service account, the client authenticates with a
+// self-signed JWT instead of round-tripping to the OAuth token endpoint.
+func NewClient(ctx context.Context, settings *Settings) (*http.Client, error) {
+	if settings == nil {
+		settings = &Settings{}
+	}
+	ts, err := clientTokenSource(ctx, settings)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Client{
+		Transport: &authTransport{
+			tokenSource:  ts,
+			quotaProject: settings.QuotaProject,
+		},
+	}, nil
+}
+
+func clientTokenSource(ctx context.Context, settings *Settings) (internal.TokenSource, error) {
+	creds, err := findJSONCredentials(ctx, settings)
+	if err != nil {
+		return nil, err
+	}
+	if settings.Scope == "" && settings.Audience != "" && len(creds.JSON) > 0 {
+		var typ struct {
+			Type string `json:"type"`
+		}
+		if err := json.Unmarshal(creds.JSON, &typ); err == nil && typ.Type == "service_account" {
+			ts, err := credentials.JWTAccessTokenSourceFromJSON(creds.JSON, settings.Audience)
+			if err != nil {
+				return nil, err
+			}
+			return ts, nil
+		}
+	}
+	return creds.TokenSource, nil
+}
+
+// authTransport wraps a base http.RoundTripper, injecting credentials into
+// every outgoing request.
+type authTransport struct {
+	base         http.RoundTripper
+	tokenSource  internal.TokenSource
+	quotaProject string
+}
+
+func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	tok, err := t.tokenSource.Token()
+	if err != nil {
+		return nil, err
+	}
+	req2 := req.Clone(req.Context())
+	tok.SetAuthHeader(req2)
+	if t.quotaProject != "" {
+		req2.Header.Set("X-Goog-User-Project", t.quotaProject)
+	}
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return base.RoundTrip(req2)
+}
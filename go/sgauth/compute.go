@@ -0,0 +1,90 @@
+//
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package sgauth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/compute/metadata"
+	"golang.org/x/oauth2"
+
+	"github.com/google/oauth2l/go/sgauth/internal"
+)
+
+// ComputeTokenSource returns a TokenSource that fetches access tokens
+// from the GCE metadata server for the given service account. An empty
+// account requests the instance's default service account.
+func ComputeTokenSource(account string) internal.TokenSource {
+	return oauth2.ReuseTokenSource(nil, computeSource{account: account})
+}
+
+// AppEngineTokenSource returns a TokenSource that fetches access tokens
+// through the classic App Engine standard environment API.
+func AppEngineTokenSource(ctx context.Context, scope string) internal.TokenSource {
+	scopes := strings.Split(scope, " ")
+	return oauth2.ReuseTokenSource(nil, appEngineTokenSource{ctx: ctx, scopes: scopes})
+}
+
+type computeSource struct {
+	account string
+}
+
+func (cs computeSource) Token() (*oauth2.Token, error) {
+	account := cs.account
+	if account == "" {
+		account = "default"
+	}
+	tokenURI := "instance/service-accounts/" + account + "/token"
+	tokenJSON, err := metadata.Get(tokenURI)
+	if err != nil {
+		return nil, err
+	}
+	var res struct {
+		AccessToken  string `json:"access_token"`
+		ExpiresInSec int    `json:"expires_in"`
+		TokenType    string `json:"token_type"`
+	}
+	if err := json.NewDecoder(strings.NewReader(tokenJSON)).Decode(&res); err != nil {
+		return nil, fmt.Errorf("sgauth: invalid response from metadata service: %v", err)
+	}
+	if res.ExpiresInSec == 0 || res.AccessToken == "" {
+		return nil, fmt.Errorf("sgauth: incomplete token received from metadata service")
+	}
+	return &oauth2.Token{
+		AccessToken: res.AccessToken,
+		TokenType:   res.TokenType,
+		Expiry:      time.Now().Add(time.Duration(res.ExpiresInSec) * time.Second),
+	}, nil
+}
+
+type appEngineTokenSource struct {
+	ctx    context.Context
+	scopes []string
+}
+
+func (ts appEngineTokenSource) Token() (*oauth2.Token, error) {
+	if appengineTokenFunc == nil {
+		return nil, fmt.Errorf("sgauth: App Engine standard environment requires a build with the 'appengine' tag")
+	}
+	token, expiry, err := appengineTokenFunc(ts.ctx, ts.scopes...)
+	if err != nil {
+		return nil, err
+	}
+	return &oauth2.Token{AccessToken: token, Expiry: expiry}, nil
+}
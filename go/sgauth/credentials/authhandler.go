@@ -0,0 +1,56 @@
+//
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package credentials
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+)
+
+// AuthorizationHandler drives the user-facing half of a 3-legged OAuth
+// flow. It is given the authorization URL to send the user to (already
+// carrying the PKCE code_challenge), the anti-CSRF state sgauth expects
+// back, and the PKCE code_verifier generated for this flow, and must
+// return the authorization code the user obtained, whatever state value
+// it actually received (callers compare it against the state they passed
+// in before trusting the code), and the redirect_uri that was actually
+// used to obtain the code. Implementations that send the authorization
+// request to the redirect_uri already present in authCodeURL (e.g.
+// ConsoleHandler's out-of-band flow) may return an empty redirectURI to
+// have the caller's own redirect_uri used for the token exchange;
+// implementations that substitute their own, like LoopbackHandler, must
+// return it so the exchange request matches what the authorization
+// server actually saw.
+type AuthorizationHandler interface {
+	Authorize(authCodeURL, state, codeVerifier string) (code, receivedState, redirectURI string, err error)
+}
+
+// newCodeVerifier generates an RFC 7636 compliant PKCE code_verifier: a
+// random string of 43-128 characters from [A-Z a-z 0-9 - . _ ~]. 32 random
+// bytes base64url-encoded without padding yields 43 characters.
+func newCodeVerifier() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// codeChallengeS256 derives the RFC 7636 S256 code_challenge from verifier.
+func codeChallengeS256(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
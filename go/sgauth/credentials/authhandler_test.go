@@ -0,0 +1,50 @@
+//
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package credentials
+
+import (
+	"encoding/base64"
+	"testing"
+)
+
+func TestNewCodeVerifier(t *testing.T) {
+	verifier, err := newCodeVerifier()
+	if err != nil {
+		t.Fatalf("newCodeVerifier() returned error: %v", err)
+	}
+	if len(verifier) < 43 || len(verifier) > 128 {
+		t.Errorf("newCodeVerifier() length = %d, want between 43 and 128 (RFC 7636)", len(verifier))
+	}
+	if _, err := base64.RawURLEncoding.DecodeString(verifier); err != nil {
+		t.Errorf("newCodeVerifier() = %q is not valid unpadded base64url: %v", verifier, err)
+	}
+
+	other, err := newCodeVerifier()
+	if err != nil {
+		t.Fatalf("newCodeVerifier() returned error: %v", err)
+	}
+	if verifier == other {
+		t.Errorf("newCodeVerifier() returned the same value twice: %q", verifier)
+	}
+}
+
+func TestCodeChallengeS256(t *testing.T) {
+	// Test vector from RFC 7636 Appendix B.
+	const verifier = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	const want = "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+	if got := codeChallengeS256(verifier); got != want {
+		t.Errorf("codeChallengeS256(%q) = %q, want %q", verifier, got, want)
+	}
+}
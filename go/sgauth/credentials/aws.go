@@ -0,0 +1,268 @@
+//
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package credentials
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// awsSecurityCredentials mirrors the JSON returned by the EC2/ECS metadata
+// service under a role's security-credentials path.
+type awsSecurityCredentials struct {
+	AccessKeyID     string `json:"AccessKeyId"`
+	SecretAccessKey string `json:"SecretAccessKey"`
+	Token           string `json:"Token"`
+}
+
+// awsSubjectToken produces the subject token required by the Google STS
+// for aws4_request credentials: a URL-escaped JSON serialization of a
+// SigV4-signed "GetCallerIdentity" request, as described by
+// https://google.aip.dev/auth/4117.
+func awsSubjectToken(cs *CredentialSource, audience string) (string, error) {
+	imdsToken, err := awsIMDSv2Token(cs)
+	if err != nil {
+		return "", fmt.Errorf("could not fetch IMDSv2 session token: %v", err)
+	}
+	region, err := awsRegion(cs, imdsToken)
+	if err != nil {
+		return "", fmt.Errorf("could not determine AWS region: %v", err)
+	}
+	creds, err := awsCredentials(cs, imdsToken)
+	if err != nil {
+		return "", fmt.Errorf("could not load AWS security credentials: %v", err)
+	}
+
+	verificationURL := cs.RegionalCredVerificationURL
+	if verificationURL == "" {
+		verificationURL = "https://sts.{region}.amazonaws.com?Action=GetCallerIdentity&Version=2011-06-15"
+	}
+	reqURL := strings.Replace(verificationURL, "{region}", region, 1)
+
+	req, err := http.NewRequest("POST", reqURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("x-goog-cloud-target-resource", audience)
+	if err := signAWSRequest(req, region, "sts", creds); err != nil {
+		return "", err
+	}
+
+	type header struct {
+		Key   string `json:"key"`
+		Value string `json:"value"`
+	}
+	headers := make([]header, 0, len(req.Header))
+	for k := range req.Header {
+		headers = append(headers, header{Key: k, Value: req.Header.Get(k)})
+	}
+	sort.Slice(headers, func(i, j int) bool { return headers[i].Key < headers[j].Key })
+
+	signedReq := struct {
+		URL     string   `json:"url"`
+		Method  string   `json:"method"`
+		Headers []header `json:"headers"`
+	}{
+		URL:     reqURL,
+		Method:  "POST",
+		Headers: headers,
+	}
+	b, err := json.Marshal(signedReq)
+	if err != nil {
+		return "", err
+	}
+	return url.QueryEscape(string(b)), nil
+}
+
+func awsRegion(cs *CredentialSource, imdsToken string) (string, error) {
+	if r := os.Getenv("AWS_REGION"); r != "" {
+		return r, nil
+	}
+	if r := os.Getenv("AWS_DEFAULT_REGION"); r != "" {
+		return r, nil
+	}
+	if cs.RegionURL == "" {
+		return "", fmt.Errorf("credential_source has no region_url and no AWS_REGION is set")
+	}
+	b, err := metadataGet(cs.RegionURL, imdsToken)
+	if err != nil {
+		return "", err
+	}
+	// region_url returns an availability zone such as "us-east-1a"; the
+	// region is that string with the trailing letter stripped.
+	az := strings.TrimSpace(b)
+	if len(az) < 2 {
+		return "", fmt.Errorf("unexpected availability zone %q", az)
+	}
+	return az[:len(az)-1], nil
+}
+
+func awsCredentials(cs *CredentialSource, imdsToken string) (awsSecurityCredentials, error) {
+	if cs.URL == "" {
+		return awsSecurityCredentials{}, fmt.Errorf("credential_source has no url to fetch security credentials from")
+	}
+	role, err := metadataGet(cs.URL, imdsToken)
+	if err != nil {
+		return awsSecurityCredentials{}, err
+	}
+	body, err := metadataGet(strings.TrimRight(cs.URL, "/")+"/"+strings.TrimSpace(role), imdsToken)
+	if err != nil {
+		return awsSecurityCredentials{}, err
+	}
+	var creds awsSecurityCredentials
+	if err := json.Unmarshal([]byte(body), &creds); err != nil {
+		return awsSecurityCredentials{}, fmt.Errorf("could not parse security credentials: %v", err)
+	}
+	return creds, nil
+}
+
+// awsIMDSv2Token fetches an IMDSv2 session token from
+// cs.IMDSv2SessionTokenURL, if set. Instances with IMDSv2 enforced reject
+// the unauthenticated GETs metadataGet otherwise makes, so callers must
+// attach this as the X-aws-ec2-metadata-token header. An empty
+// IMDSv2SessionTokenURL returns an empty token, since IMDSv1-only
+// environments (and ECS, which uses a different, pre-authenticated
+// metadata endpoint) have no use for one.
+func awsIMDSv2Token(cs *CredentialSource) (string, error) {
+	if cs.IMDSv2SessionTokenURL == "" {
+		return "", nil
+	}
+	req, err := http.NewRequest("PUT", cs.IMDSv2SessionTokenURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", "300")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("imdsv2 token request to %s returned status %d", cs.IMDSv2SessionTokenURL, resp.StatusCode)
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+func metadataGet(u, imdsToken string) (string, error) {
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return "", err
+	}
+	if imdsToken != "" {
+		req.Header.Set("X-aws-ec2-metadata-token", imdsToken)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("metadata request to %s returned status %d", u, resp.StatusCode)
+	}
+	return string(b), nil
+}
+
+// signAWSRequest signs req in place using AWS Signature Version 4,
+// setting the Authorization, X-Amz-Date and X-Amz-Security-Token headers.
+func signAWSRequest(req *http.Request, region, service string, creds awsSecurityCredentials) error {
+	now := time.Now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	dateStamp := now.Format("20060102")
+
+	req.Header.Set("host", req.URL.Host)
+	req.Header.Set("x-amz-date", amzDate)
+	if creds.Token != "" {
+		req.Header.Set("x-amz-security-token", creds.Token)
+	}
+
+	signedHeaderNames, canonicalHeaders := canonicalAWSHeaders(req)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.Path,
+		req.URL.RawQuery,
+		canonicalHeaders,
+		signedHeaderNames,
+		sha256Hex(""),
+	}, "\n")
+
+	credentialScope := fmt.Sprintf("%s/%s/%s/aws4_request", dateStamp, region, service)
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		credentialScope,
+		sha256Hex(canonicalRequest),
+	}, "\n")
+
+	signingKey := awsSigningKey(creds.SecretAccessKey, dateStamp, region, service)
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	authHeader := fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		creds.AccessKeyID, credentialScope, signedHeaderNames, signature)
+	req.Header.Set("Authorization", authHeader)
+	return nil
+}
+
+func canonicalAWSHeaders(req *http.Request) (signedHeaderNames, canonicalHeaders string) {
+	names := make([]string, 0, len(req.Header))
+	for k := range req.Header {
+		names = append(names, strings.ToLower(k))
+	}
+	sort.Strings(names)
+
+	var sb strings.Builder
+	for _, n := range names {
+		sb.WriteString(n)
+		sb.WriteString(":")
+		sb.WriteString(strings.TrimSpace(req.Header.Get(n)))
+		sb.WriteString("\n")
+	}
+	return strings.Join(names, ";"), sb.String()
+}
+
+func awsSigningKey(secret, dateStamp, region, service string) []byte {
+	kDate := hmacSHA256([]byte("AWS4"+secret), dateStamp)
+	kRegion := hmacSHA256(kDate, region)
+	kService := hmacSHA256(kRegion, service)
+	return hmacSHA256(kService, "aws4_request")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func sha256Hex(data string) string {
+	h := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(h[:])
+}
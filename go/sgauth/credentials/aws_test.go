@@ -0,0 +1,84 @@
+//
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package credentials
+
+import (
+	"encoding/hex"
+	"net/http"
+	"testing"
+)
+
+func TestAwsSigningKey(t *testing.T) {
+	// Expected value independently derived from the HMAC-SHA256 chain
+	// described in AWS's SigV4 "derive a signing key" algorithm:
+	// kSecret -> kDate -> kRegion -> kService -> kSigning.
+	const secret = "wJalrXUtnFEMI/K7MDENG/bPxRfiCYEXAMPLEKEY"
+	const want = "2c94c0cf5378ada6887f09bb697df8fc0affdb34ba1cdd5bda32b664bd55b73c"
+
+	got := awsSigningKey(secret, "20150830", "us-east-1", "iam")
+	if hex.EncodeToString(got) != want {
+		t.Errorf("awsSigningKey() = %x, want %s", got, want)
+	}
+}
+
+func TestCanonicalAWSHeaders(t *testing.T) {
+	req, err := http.NewRequest("POST", "https://sts.us-east-1.amazonaws.com", nil)
+	if err != nil {
+		t.Fatalf("NewRequest: %v", err)
+	}
+	req.Header.Set("host", "sts.us-east-1.amazonaws.com")
+	req.Header.Set("x-amz-date", "20150830T123600Z")
+
+	signedHeaderNames, canonicalHeaders := canonicalAWSHeaders(req)
+
+	const wantNames = "host;x-amz-date"
+	if signedHeaderNames != wantNames {
+		t.Errorf("canonicalAWSHeaders() signedHeaderNames = %q, want %q", signedHeaderNames, wantNames)
+	}
+	const wantHeaders = "host:sts.us-east-1.amazonaws.com\nx-amz-date:20150830T123600Z\n"
+	if canonicalHeaders != wantHeaders {
+		t.Errorf("canonicalAWSHeaders() canonicalHeaders = %q, want %q", canonicalHeaders, wantHeaders)
+	}
+}
+
+func TestSignAWSRequestSetsAuthorizationHeader(t *testing.T) {
+	req, err := http.NewRequest("POST", "https://sts.us-east-1.amazonaws.com?Action=GetCallerIdentity&Version=2011-06-15", nil)
+	if err != nil {
+		t.Fatalf("NewRequest: %v", err)
+	}
+	creds := awsSecurityCredentials{
+		AccessKeyID:     "AKIDEXAMPLE",
+		SecretAccessKey: "wJalrXUtnFEMI/K7MDENG/bPxRfiCYEXAMPLEKEY",
+		Token:           "sessiontoken",
+	}
+	if err := signAWSRequest(req, "us-east-1", "sts", creds); err != nil {
+		t.Fatalf("signAWSRequest() returned error: %v", err)
+	}
+
+	auth := req.Header.Get("Authorization")
+	if auth == "" {
+		t.Fatal("signAWSRequest() did not set an Authorization header")
+	}
+	wantPrefix := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/"
+	if len(auth) < len(wantPrefix) || auth[:len(wantPrefix)] != wantPrefix {
+		t.Errorf("Authorization header = %q, want prefix %q", auth, wantPrefix)
+	}
+	if req.Header.Get("x-amz-security-token") != "sessiontoken" {
+		t.Errorf("x-amz-security-token = %q, want %q", req.Header.Get("x-amz-security-token"), "sessiontoken")
+	}
+	if req.Header.Get("x-amz-date") == "" {
+		t.Error("signAWSRequest() did not set x-amz-date")
+	}
+}
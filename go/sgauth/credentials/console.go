@@ -0,0 +1,52 @@
+//
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package credentials
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// ConsoleHandler is an AuthorizationHandler for headless environments: it
+// prints the authorization URL and prompts the user to paste back the
+// authorization code obtained out-of-band. Since there is no callback to
+// receive it on, the state it returns is simply the state it was given.
+type ConsoleHandler struct {
+	// Output defaults to os.Stdout.
+	Output io.Writer
+	// Input defaults to os.Stdin.
+	Input io.Reader
+}
+
+// Authorize implements AuthorizationHandler. It leaves redirectURI empty
+// since it sends the user to whatever redirect_uri is already baked into
+// authCodeURL, unchanged.
+func (h *ConsoleHandler) Authorize(authCodeURL, state, codeVerifier string) (string, string, string, error) {
+	out := h.Output
+	if out == nil {
+		out = os.Stdout
+	}
+	in := h.Input
+	if in == nil {
+		in = os.Stdin
+	}
+	fmt.Fprintf(out, "Go to the following link in your browser, then type the authorization code:\n\n  %s\n\nCode: ", authCodeURL)
+	var code string
+	if _, err := fmt.Fscan(in, &code); err != nil {
+		return "", "", "", fmt.Errorf("sgauth/credentials: could not read authorization code: %v", err)
+	}
+	return code, state, "", nil
+}
@@ -0,0 +1,119 @@
+//
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package credentials parses the various JSON credential file formats
+// recognized by Google client libraries and turns them into token sources.
+package credentials
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/oauth2l/go/sgauth/internal"
+)
+
+// Credential type discriminators, as found in the "type" field of a
+// credentials JSON file.
+const (
+	serviceAccountKey  = "service_account"
+	userCredentialsKey = "authorized_user"
+	externalAccountKey = "external_account"
+)
+
+// Credentials holds Google credentials, including "Application Default
+// Credentials". See the Credentials.TokenSource for the source of the
+// tokens and ProjectID for the associated project ID.
+type Credentials struct {
+	ProjectID   string // may be empty
+	TokenSource internal.TokenSource
+	JSON        []byte
+}
+
+// File represents the contents of a Google credentials JSON file, which may
+// describe a service account key, end-user (authorized_user) credentials,
+// or other credential types supported by Google client libraries.
+type File struct {
+	Type string `json:"type"`
+
+	// Service Account fields
+	ClientEmail  string `json:"client_email"`
+	PrivateKeyID string `json:"private_key_id"`
+	PrivateKey   string `json:"private_key"`
+	AuthURL      string `json:"auth_uri"`
+	TokenURL     string `json:"token_uri"`
+	ProjectID    string `json:"project_id"`
+
+	// User Credential fields
+	// (These typically come from gcloud auth application-default login.)
+	ClientSecret string `json:"client_secret"`
+	ClientID     string `json:"client_id"`
+	RefreshToken string `json:"refresh_token"`
+
+	// External Account fields (Workload Identity Federation)
+	Audience                       string            `json:"audience"`
+	SubjectTokenType               string            `json:"subject_token_type"`
+	STSTokenURL                    string            `json:"token_url"`
+	ServiceAccountImpersonationURL string            `json:"service_account_impersonation_url"`
+	CredentialSource               *CredentialSource `json:"credential_source"`
+}
+
+// CredentialSource describes where an external_account credential should
+// read its third-party "subject token" from before exchanging it with the
+// Google Security Token Service.
+type CredentialSource struct {
+	// File is a path to a file containing the subject token.
+	File string `json:"file"`
+
+	// URL is an HTTP(S) endpoint returning the subject token in its body.
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers"`
+
+	// AWS fields. EnvironmentID is "aws1" for the only defined version.
+	EnvironmentID               string `json:"environment_id"`
+	RegionURL                   string `json:"region_url"`
+	RegionalCredVerificationURL string `json:"regional_cred_verification_url"`
+	IMDSv2SessionTokenURL       string `json:"imdsv2_session_token_url"`
+
+	// Executable, if set, sources the subject token from a local helper
+	// binary instead of a file, URL, or cloud metadata server.
+	Executable *ExecutableConfig `json:"executable"`
+}
+
+// ExecutableConfig describes a "pluggable auth" helper binary that prints
+// a subject token (or SAML response) as JSON on stdout.
+type ExecutableConfig struct {
+	Command       string `json:"command"`
+	TimeoutMillis int    `json:"timeout_millis"`
+	OutputFile    string `json:"output_file"`
+}
+
+// TokenSource returns a TokenSource derived from the credential type
+// described by f, restricted to scopes. handler and state are only used
+// for the interactive "authorized_user" 3-legged OAuth flow, when no
+// refresh token is already present.
+func (f *File) TokenSource(ctx context.Context, scopes []string, handler AuthorizationHandler, state string) (internal.TokenSource, error) {
+	switch f.Type {
+	case serviceAccountKey:
+		return f.serviceAccountTokenSource(ctx, scopes)
+	case userCredentialsKey:
+		return f.userCredentialsTokenSource(ctx, scopes, handler, state)
+	case externalAccountKey:
+		return f.externalAccountTokenSource(ctx, scopes)
+	case "":
+		return nil, fmt.Errorf("sgauth/credentials: missing 'type' field in credentials")
+	default:
+		return nil, fmt.Errorf("sgauth/credentials: unknown credential type %q", f.Type)
+	}
+}
@@ -0,0 +1,215 @@
+//
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package credentials
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const allowExecutablesEnvVar = "GOOGLE_EXTERNAL_ACCOUNT_ALLOW_EXECUTABLES"
+
+// executableResponse is the JSON contract a pluggable-auth helper binary
+// must print to stdout, per
+// https://google.aip.dev/auth/4117#determining-subject-token-type-in-response.
+type executableResponse struct {
+	Version        int    `json:"version"`
+	Success        bool   `json:"success"`
+	TokenType      string `json:"token_type"`
+	IDToken        string `json:"id_token"`
+	SAMLResponse   string `json:"saml_response"`
+	ExpirationTime int64  `json:"expiration_time"`
+	Code           string `json:"code"`
+	Message        string `json:"message"`
+}
+
+func (r executableResponse) subjectToken() (string, error) {
+	switch {
+	case r.IDToken != "":
+		return r.IDToken, nil
+	case r.SAMLResponse != "":
+		return r.SAMLResponse, nil
+	default:
+		return "", fmt.Errorf("executable response has neither id_token nor saml_response")
+	}
+}
+
+// executableSubjectToken retrieves the subject token by invoking the
+// helper binary described by credential_source.executable, reusing a
+// cached, unexpired result from output_file when available.
+func (ts *externalAccountSource) executableSubjectToken() (string, error) {
+	cfg := ts.file.CredentialSource.Executable
+	if cfg.OutputFile != "" {
+		if tok, err := readCachedExecutableToken(cfg.OutputFile); err == nil {
+			return tok, nil
+		}
+	}
+
+	if os.Getenv(allowExecutablesEnvVar) != "1" {
+		return "", fmt.Errorf("credential_source.executable requires %s=1 to be set", allowExecutablesEnvVar)
+	}
+
+	timeout := 30 * time.Second
+	if cfg.TimeoutMillis > 0 {
+		timeout = time.Duration(cfg.TimeoutMillis) * time.Millisecond
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	args, err := splitShellCommand(cfg.Command)
+	if err != nil {
+		return "", fmt.Errorf("credential_source.executable has an invalid command: %v", err)
+	}
+	if len(args) == 0 {
+		return "", fmt.Errorf("credential_source.executable has an empty command")
+	}
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Env = append(os.Environ(),
+		"GOOGLE_EXTERNAL_ACCOUNT_AUDIENCE="+ts.file.Audience,
+		"GOOGLE_EXTERNAL_ACCOUNT_TOKEN_TYPE="+ts.file.SubjectTokenType,
+		"GOOGLE_EXTERNAL_ACCOUNT_INTERACTIVE=0",
+	)
+	if email := impersonatedEmail(ts.file.ServiceAccountImpersonationURL); email != "" {
+		cmd.Env = append(cmd.Env, "GOOGLE_EXTERNAL_ACCOUNT_IMPERSONATED_EMAIL="+email)
+	}
+
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("credential_source.executable command failed: %v", err)
+	}
+
+	var resp executableResponse
+	if err := json.Unmarshal(out, &resp); err != nil {
+		return "", fmt.Errorf("could not parse executable response: %v", err)
+	}
+	if !resp.Success {
+		return "", fmt.Errorf("executable reported failure: code=%s message=%s", resp.Code, resp.Message)
+	}
+	token, err := resp.subjectToken()
+	if err != nil {
+		return "", err
+	}
+
+	if cfg.OutputFile != "" {
+		// Best effort; a failed cache write shouldn't fail the request.
+		_ = ioutil.WriteFile(cfg.OutputFile, out, 0600)
+	}
+	return token, nil
+}
+
+// readCachedExecutableToken returns the subject token from a previous
+// executableResponse cached at path, as long as it has not yet expired.
+func readCachedExecutableToken(path string) (string, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	var resp executableResponse
+	if err := json.Unmarshal(b, &resp); err != nil {
+		return "", err
+	}
+	if !resp.Success {
+		return "", fmt.Errorf("cached executable response reports failure")
+	}
+	if resp.ExpirationTime != 0 && time.Now().Unix() >= resp.ExpirationTime {
+		return "", fmt.Errorf("cached executable response has expired")
+	}
+	return resp.subjectToken()
+}
+
+// splitShellCommand tokenizes a credential_source.executable command line
+// the way a POSIX shell would, so quoted arguments (a path containing
+// spaces, a quoted JSON flag value, etc.) survive intact instead of being
+// split on every whitespace run. It supports single quotes (literal),
+// double quotes (where backslash only escapes a following " or \), and
+// backslash-escaping outside quotes; it does not expand variables, globs,
+// or other shell syntax.
+func splitShellCommand(command string) ([]string, error) {
+	runes := []rune(command)
+	var args []string
+	var cur strings.Builder
+	hasToken := false
+	inSingle, inDouble, escaped := false, false, false
+
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case escaped:
+			cur.WriteRune(r)
+			escaped = false
+		case inSingle:
+			if r == '\'' {
+				inSingle = false
+			} else {
+				cur.WriteRune(r)
+			}
+		case inDouble:
+			switch {
+			case r == '"':
+				inDouble = false
+			case r == '\\' && i+1 < len(runes) && (runes[i+1] == '"' || runes[i+1] == '\\'):
+				escaped = true
+			default:
+				cur.WriteRune(r)
+			}
+		case r == '\\':
+			escaped = true
+			hasToken = true
+		case r == '\'':
+			inSingle = true
+			hasToken = true
+		case r == '"':
+			inDouble = true
+			hasToken = true
+		case r == ' ' || r == '\t':
+			if hasToken {
+				args = append(args, cur.String())
+				cur.Reset()
+				hasToken = false
+			}
+		default:
+			cur.WriteRune(r)
+			hasToken = true
+		}
+	}
+	if inSingle || inDouble || escaped {
+		return nil, fmt.Errorf("unterminated quote or escape in command %q", command)
+	}
+	if hasToken {
+		args = append(args, cur.String())
+	}
+	return args, nil
+}
+
+// impersonatedEmail extracts the target service account email from a
+// ".../serviceAccounts/{email}:generateAccessToken" IAM Credentials URL.
+func impersonatedEmail(impersonationURL string) string {
+	const marker = "/serviceAccounts/"
+	i := strings.Index(impersonationURL, marker)
+	if i < 0 {
+		return ""
+	}
+	rest := impersonationURL[i+len(marker):]
+	if j := strings.IndexByte(rest, ':'); j >= 0 {
+		return rest[:j]
+	}
+	return rest
+}
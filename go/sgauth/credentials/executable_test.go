@@ -0,0 +1,107 @@
+//
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package credentials
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitShellCommand(t *testing.T) {
+	tests := []struct {
+		name    string
+		command string
+		want    []string
+	}{
+		{
+			name:    "simple",
+			command: "/usr/bin/helper --flag value",
+			want:    []string{"/usr/bin/helper", "--flag", "value"},
+		},
+		{
+			name:    "double-quoted path with spaces",
+			command: `"C:\Program Files\helper.exe" --flag a`,
+			want:    []string{`C:\Program Files\helper.exe`, "--flag", "a"},
+		},
+		{
+			name:    "quoted argument containing a space",
+			command: `helper --flag"a b"`,
+			want:    []string{"helper", "--flaga b"},
+		},
+		{
+			name:    "single-quoted argument",
+			command: `helper '--flag=a b'`,
+			want:    []string{"helper", "--flag=a b"},
+		},
+		{
+			name:    "backslash-escaped space outside quotes",
+			command: `helper --flag=a\ b`,
+			want:    []string{"helper", "--flag=a b"},
+		},
+		{
+			name:    "extra whitespace between tokens",
+			command: "  helper   --flag  ",
+			want:    []string{"helper", "--flag"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := splitShellCommand(tt.command)
+			if err != nil {
+				t.Fatalf("splitShellCommand(%q) returned error: %v", tt.command, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitShellCommand(%q) = %#v, want %#v", tt.command, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitShellCommandUnterminatedQuote(t *testing.T) {
+	if _, err := splitShellCommand(`helper "unterminated`); err == nil {
+		t.Error("splitShellCommand() returned nil error for an unterminated quote, want one")
+	}
+}
+
+func TestImpersonatedEmail(t *testing.T) {
+	tests := []struct {
+		name string
+		url  string
+		want string
+	}{
+		{
+			name: "generateAccessToken",
+			url:  "https://iamcredentials.googleapis.com/v1/projects/-/serviceAccounts/my-sa@my-project.iam.gserviceaccount.com:generateAccessToken",
+			want: "my-sa@my-project.iam.gserviceaccount.com",
+		},
+		{
+			name: "no trailing method",
+			url:  "https://iamcredentials.googleapis.com/v1/projects/-/serviceAccounts/my-sa@my-project.iam.gserviceaccount.com",
+			want: "my-sa@my-project.iam.gserviceaccount.com",
+		},
+		{
+			name: "no match",
+			url:  "https://sts.googleapis.com/v1/token",
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := impersonatedEmail(tt.url); got != tt.want {
+				t.Errorf("impersonatedEmail(%q) = %q, want %q", tt.url, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+//
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package credentials
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+
+	"github.com/google/oauth2l/go/sgauth/internal"
+)
+
+const defaultSTSTokenURL = "https://sts.googleapis.com/v1/token"
+
+// externalAccountTokenSource builds a TokenSource for Workload Identity
+// Federation: it retrieves a subject token from the configured
+// credential_source, exchanges it with the Google STS for a federated
+// access token, and - if service_account_impersonation_url is set -
+// exchanges that federated token for a service account access token.
+func (f *File) externalAccountTokenSource(ctx context.Context, scopes []string) (internal.TokenSource, error) {
+	if f.CredentialSource == nil {
+		return nil, fmt.Errorf("sgauth/credentials: external_account requires a credential_source")
+	}
+	ts := &externalAccountSource{file: f, scopes: scopes}
+	tok, err := ts.Token()
+	if err != nil {
+		return nil, err
+	}
+	return oauth2.ReuseTokenSource(tok, ts), nil
+}
+
+type externalAccountSource struct {
+	file   *File
+	scopes []string
+}
+
+func (ts *externalAccountSource) Token() (*oauth2.Token, error) {
+	subjectToken, err := ts.subjectToken()
+	if err != nil {
+		return nil, fmt.Errorf("sgauth/credentials: could not obtain subject token: %v", err)
+	}
+	tok, err := ts.exchangeSubjectToken(subjectToken)
+	if err != nil {
+		return nil, err
+	}
+	if ts.file.ServiceAccountImpersonationURL == "" {
+		return tok, nil
+	}
+	return generateAccessToken(ts.file.ServiceAccountImpersonationURL, tok.AccessToken, nil, ts.scopes, 0)
+}
+
+// subjectToken retrieves the third-party token described by
+// f.CredentialSource, from a file, an HTTP endpoint, or the AWS instance
+// metadata service.
+func (ts *externalAccountSource) subjectToken() (string, error) {
+	cs := ts.file.CredentialSource
+	switch {
+	case cs.Executable != nil:
+		return ts.executableSubjectToken()
+	case cs.File != "":
+		b, err := ioutil.ReadFile(cs.File)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(b)), nil
+	case cs.EnvironmentID != "":
+		if !strings.HasPrefix(cs.EnvironmentID, "aws") {
+			return "", fmt.Errorf("unsupported environment_id %q", cs.EnvironmentID)
+		}
+		return awsSubjectToken(cs, ts.file.Audience)
+	case cs.URL != "":
+		req, err := http.NewRequest("GET", cs.URL, nil)
+		if err != nil {
+			return "", err
+		}
+		for k, v := range cs.Headers {
+			req.Header.Set(k, v)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		b, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return "", err
+		}
+		if resp.StatusCode != http.StatusOK {
+			return "", fmt.Errorf("credential_source url returned status %d: %s", resp.StatusCode, b)
+		}
+		return strings.TrimSpace(string(b)), nil
+	default:
+		return "", fmt.Errorf("credential_source has no file, url, or environment_id")
+	}
+}
+
+// exchangeSubjectToken performs the OAuth 2.0 Token Exchange (RFC 8693)
+// against the Google Security Token Service, trading the subject token for
+// a short-lived federated access token.
+func (ts *externalAccountSource) exchangeSubjectToken(subjectToken string) (*oauth2.Token, error) {
+	stsURL := ts.file.STSTokenURL
+	if stsURL == "" {
+		stsURL = defaultSTSTokenURL
+	}
+	v := url.Values{}
+	v.Set("grant_type", "urn:ietf:params:oauth:grant-type:token-exchange")
+	v.Set("audience", ts.file.Audience)
+	v.Set("scope", strings.Join(ts.scopes, " "))
+	v.Set("requested_token_type", "urn:ietf:params:oauth:token-type:access_token")
+	v.Set("subject_token", subjectToken)
+	v.Set("subject_token_type", ts.file.SubjectTokenType)
+
+	resp, err := http.PostForm(stsURL, v)
+	if err != nil {
+		return nil, fmt.Errorf("sgauth/credentials: STS token exchange failed: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("sgauth/credentials: STS token exchange returned status %d: %s", resp.StatusCode, body)
+	}
+	var res struct {
+		AccessToken     string `json:"access_token"`
+		IssuedTokenType string `json:"issued_token_type"`
+		TokenType       string `json:"token_type"`
+		ExpiresIn       int    `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body, &res); err != nil {
+		return nil, fmt.Errorf("sgauth/credentials: could not parse STS response: %v", err)
+	}
+	return &oauth2.Token{
+		AccessToken: res.AccessToken,
+		TokenType:   res.TokenType,
+		Expiry:      time.Now().Add(time.Duration(res.ExpiresIn) * time.Second),
+	}, nil
+}
@@ -0,0 +1,134 @@
+//
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package credentials
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestExchangeSubjectToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Fatalf("ParseForm: %v", err)
+		}
+		if got, want := r.Form.Get("grant_type"), "urn:ietf:params:oauth:grant-type:token-exchange"; got != want {
+			t.Errorf("grant_type = %q, want %q", got, want)
+		}
+		if got, want := r.Form.Get("subject_token"), "the-subject-token"; got != want {
+			t.Errorf("subject_token = %q, want %q", got, want)
+		}
+		if got, want := r.Form.Get("audience"), "//iam.googleapis.com/projects/123/locations/global/workloadIdentityPools/pool/providers/provider"; got != want {
+			t.Errorf("audience = %q, want %q", got, want)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token":"federated-token","issued_token_type":"urn:ietf:params:oauth:token-type:access_token","token_type":"Bearer","expires_in":3600}`))
+	}))
+	defer server.Close()
+
+	ts := &externalAccountSource{
+		file: &File{
+			Audience:         "//iam.googleapis.com/projects/123/locations/global/workloadIdentityPools/pool/providers/provider",
+			SubjectTokenType: "urn:ietf:params:oauth:token-type:jwt",
+			STSTokenURL:      server.URL,
+		},
+		scopes: []string{"https://www.googleapis.com/auth/cloud-platform"},
+	}
+
+	tok, err := ts.exchangeSubjectToken("the-subject-token")
+	if err != nil {
+		t.Fatalf("exchangeSubjectToken() returned error: %v", err)
+	}
+	if tok.AccessToken != "federated-token" {
+		t.Errorf("AccessToken = %q, want %q", tok.AccessToken, "federated-token")
+	}
+	if wantExpiry := time.Now().Add(3600 * time.Second); tok.Expiry.Before(wantExpiry.Add(-time.Minute)) || tok.Expiry.After(wantExpiry.Add(time.Minute)) {
+		t.Errorf("Expiry = %v, want close to %v", tok.Expiry, wantExpiry)
+	}
+}
+
+func TestExchangeSubjectTokenErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":"invalid_request"}`))
+	}))
+	defer server.Close()
+
+	ts := &externalAccountSource{
+		file: &File{Audience: "aud", STSTokenURL: server.URL},
+	}
+	if _, err := ts.exchangeSubjectToken("token"); err == nil {
+		t.Error("exchangeSubjectToken() returned nil error for a 400 response, want one")
+	}
+}
+
+func TestExchangeSubjectTokenMalformedJSON(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`not json`))
+	}))
+	defer server.Close()
+
+	ts := &externalAccountSource{
+		file: &File{Audience: "aud", STSTokenURL: server.URL},
+	}
+	if _, err := ts.exchangeSubjectToken("token"); err == nil {
+		t.Error("exchangeSubjectToken() returned nil error for a malformed response body, want one")
+	}
+}
+
+func TestExternalAccountTokenSourceWithImpersonation(t *testing.T) {
+	stsServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"access_token":"federated-token","token_type":"Bearer","expires_in":3600}`))
+	}))
+	defer stsServer.Close()
+
+	iamServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got, want := r.Header.Get("Authorization"), "Bearer federated-token"; got != want {
+			t.Errorf("Authorization = %q, want %q", got, want)
+		}
+		b, _ := ioutil.ReadAll(r.Body)
+		if len(b) == 0 {
+			t.Error("request body was empty")
+		}
+		w.Write([]byte(`{"accessToken":"impersonated-token","expireTime":"2099-01-01T00:00:00Z"}`))
+	}))
+	defer iamServer.Close()
+
+	tokenFile := filepath.Join(t.TempDir(), "subject-token")
+	if err := ioutil.WriteFile(tokenFile, []byte("subject-token"), 0600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	ts := &externalAccountSource{
+		file: &File{
+			Audience:                       "aud",
+			STSTokenURL:                    stsServer.URL,
+			ServiceAccountImpersonationURL: iamServer.URL,
+			CredentialSource:               &CredentialSource{File: tokenFile},
+		},
+	}
+
+	impersonated, err := ts.Token()
+	if err != nil {
+		t.Fatalf("Token() returned error: %v", err)
+	}
+	if impersonated.AccessToken != "impersonated-token" {
+		t.Errorf("AccessToken = %q, want %q", impersonated.AccessToken, "impersonated-token")
+	}
+}
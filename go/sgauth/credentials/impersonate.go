@@ -0,0 +1,247 @@
+//
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package credentials
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+
+	"github.com/google/oauth2l/go/sgauth/internal"
+)
+
+const iamCredentialsBaseURL = "https://iamcredentials.googleapis.com/v1/projects/-/serviceAccounts/"
+
+// ImpersonatedTokenSource returns a TokenSource that mints access tokens
+// for the service account target, authenticated as base. base must have
+// the "Service Account Token Creator" role on target directly, or on the
+// first entry of delegates, which must in turn grant it transitively to
+// target. A zero lifetime requests the IAM Credentials API default of one
+// hour.
+func ImpersonatedTokenSource(ctx context.Context, base internal.TokenSource, target string, delegates []string, scopes []string, lifetime time.Duration) (internal.TokenSource, error) {
+	ts := &impersonatedTokenSource{
+		base:      base,
+		url:       iamCredentialsBaseURL + target + ":generateAccessToken",
+		delegates: delegates,
+		scopes:    scopes,
+		lifetime:  lifetime,
+	}
+	tok, err := ts.Token()
+	if err != nil {
+		return nil, err
+	}
+	return oauth2.ReuseTokenSource(tok, ts), nil
+}
+
+type impersonatedTokenSource struct {
+	base      internal.TokenSource
+	url       string
+	delegates []string
+	scopes    []string
+	lifetime  time.Duration
+}
+
+func (ts *impersonatedTokenSource) Token() (*oauth2.Token, error) {
+	baseToken, err := ts.base.Token()
+	if err != nil {
+		return nil, fmt.Errorf("sgauth/credentials: could not obtain base token for impersonation: %v", err)
+	}
+	reqBody := struct {
+		Delegates []string `json:"delegates,omitempty"`
+		Scope     []string `json:"scope"`
+		Lifetime  string   `json:"lifetime,omitempty"`
+	}{
+		Delegates: ts.delegates,
+		Scope:     ts.scopes,
+	}
+	if ts.lifetime > 0 {
+		reqBody.Lifetime = fmt.Sprintf("%ds", int(ts.lifetime.Seconds()))
+	}
+	var res struct {
+		AccessToken string    `json:"accessToken"`
+		ExpireTime  time.Time `json:"expireTime"`
+	}
+	if err := callIAMCredentials(ts.url, baseToken.AccessToken, reqBody, &res); err != nil {
+		return nil, err
+	}
+	return &oauth2.Token{
+		AccessToken: res.AccessToken,
+		TokenType:   "Bearer",
+		Expiry:      res.ExpireTime,
+	}, nil
+}
+
+// generateAccessToken is a one-shot helper used by the external_account
+// flow to exchange a federated token for a service account access token
+// via service_account_impersonation_url, without the caching and
+// refreshing behavior of ImpersonatedTokenSource.
+func generateAccessToken(url, baseAccessToken string, delegates, scopes []string, lifetime time.Duration) (*oauth2.Token, error) {
+	reqBody := struct {
+		Delegates []string `json:"delegates,omitempty"`
+		Scope     []string `json:"scope"`
+		Lifetime  string   `json:"lifetime,omitempty"`
+	}{
+		Delegates: delegates,
+		Scope:     scopes,
+	}
+	if lifetime > 0 {
+		reqBody.Lifetime = fmt.Sprintf("%ds", int(lifetime.Seconds()))
+	}
+	var res struct {
+		AccessToken string    `json:"accessToken"`
+		ExpireTime  time.Time `json:"expireTime"`
+	}
+	if err := callIAMCredentials(url, baseAccessToken, reqBody, &res); err != nil {
+		return nil, err
+	}
+	return &oauth2.Token{
+		AccessToken: res.AccessToken,
+		TokenType:   "Bearer",
+		Expiry:      res.ExpireTime,
+	}, nil
+}
+
+// ImpersonatedJWTTokenSource mints a signed JWT asserting target as both
+// issuer and subject, via the IAM Credentials API's signJwt method,
+// authenticated as base. This is the JWTTokenSource equivalent for a
+// service account the caller does not hold a private key for: claims must
+// include at least "aud", "iat" and "exp".
+func ImpersonatedJWTTokenSource(ctx context.Context, base internal.TokenSource, target string, delegates []string, claims map[string]interface{}) (internal.TokenSource, error) {
+	full := make(map[string]interface{}, len(claims)+2)
+	for k, v := range claims {
+		full[k] = v
+	}
+	full["iss"] = target
+	full["sub"] = target
+	payload, err := json.Marshal(full)
+	if err != nil {
+		return nil, fmt.Errorf("sgauth/credentials: could not marshal JWT claims: %v", err)
+	}
+	ts := &impersonatedJWTTokenSource{
+		base:      base,
+		url:       iamCredentialsBaseURL + target + ":signJwt",
+		delegates: delegates,
+		payload:   string(payload),
+	}
+	tok, err := ts.Token()
+	if err != nil {
+		return nil, err
+	}
+	return oauth2.ReuseTokenSource(tok, ts), nil
+}
+
+type impersonatedJWTTokenSource struct {
+	base      internal.TokenSource
+	url       string
+	delegates []string
+	payload   string
+}
+
+func (ts *impersonatedJWTTokenSource) Token() (*oauth2.Token, error) {
+	baseToken, err := ts.base.Token()
+	if err != nil {
+		return nil, fmt.Errorf("sgauth/credentials: could not obtain base token for impersonation: %v", err)
+	}
+	reqBody := struct {
+		Delegates []string `json:"delegates,omitempty"`
+		Payload   string   `json:"payload"`
+	}{
+		Delegates: ts.delegates,
+		Payload:   ts.payload,
+	}
+	var res struct {
+		KeyID     string `json:"keyId"`
+		SignedJwt string `json:"signedJwt"`
+	}
+	if err := callIAMCredentials(ts.url, baseToken.AccessToken, reqBody, &res); err != nil {
+		return nil, err
+	}
+	exp, err := jwtExpiry(res.SignedJwt)
+	if err != nil {
+		return nil, err
+	}
+	return &oauth2.Token{AccessToken: res.SignedJwt, TokenType: "Bearer", Expiry: exp}, nil
+}
+
+// jwtExpiry extracts the "exp" claim from an unverified JWT so the result
+// can be cached with oauth2.ReuseTokenSource.
+func jwtExpiry(rawJWT string) (time.Time, error) {
+	claims, err := decodeJWTClaims(rawJWT)
+	if err != nil {
+		return time.Time{}, err
+	}
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return time.Time{}, fmt.Errorf("sgauth/credentials: signed JWT has no numeric 'exp' claim")
+	}
+	return time.Unix(int64(exp), 0), nil
+}
+
+// decodeJWTClaims base64url-decodes the payload segment of a JWT without
+// verifying its signature; it is only used to read the "exp" claim back
+// out of a JWT this same process just had IAM Credentials sign.
+func decodeJWTClaims(rawJWT string) (map[string]interface{}, error) {
+	parts := strings.Split(rawJWT, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("sgauth/credentials: malformed JWT")
+	}
+	b, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("sgauth/credentials: could not decode JWT payload: %v", err)
+	}
+	var claims map[string]interface{}
+	if err := json.Unmarshal(b, &claims); err != nil {
+		return nil, fmt.Errorf("sgauth/credentials: could not parse JWT payload: %v", err)
+	}
+	return claims, nil
+}
+
+func callIAMCredentials(url, bearerToken string, reqBody, out interface{}) error {
+	b, err := json.Marshal(reqBody)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", url, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+bearerToken)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("sgauth/credentials: IAM Credentials request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("sgauth/credentials: IAM Credentials request returned status %d: %s", resp.StatusCode, body)
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("sgauth/credentials: could not parse IAM Credentials response: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+//
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package credentials
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"golang.org/x/oauth2"
+)
+
+func TestImpersonatedTokenSourceToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got, want := r.Header.Get("Authorization"), "Bearer base-token"; got != want {
+			t.Errorf("Authorization = %q, want %q", got, want)
+		}
+		var body struct {
+			Delegates []string `json:"delegates,omitempty"`
+			Scope     []string `json:"scope"`
+			Lifetime  string   `json:"lifetime,omitempty"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatalf("could not decode request body: %v", err)
+		}
+		if want := []string{"https://www.googleapis.com/auth/cloud-platform"}; len(body.Scope) != 1 || body.Scope[0] != want[0] {
+			t.Errorf("scope = %v, want %v", body.Scope, want)
+		}
+		w.Write([]byte(`{"accessToken":"impersonated-token","expireTime":"2099-01-01T00:00:00Z"}`))
+	}))
+	defer server.Close()
+
+	ts := &impersonatedTokenSource{
+		base:   oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "base-token"}),
+		url:    server.URL,
+		scopes: []string{"https://www.googleapis.com/auth/cloud-platform"},
+	}
+	tok, err := ts.Token()
+	if err != nil {
+		t.Fatalf("Token() returned error: %v", err)
+	}
+	if tok.AccessToken != "impersonated-token" {
+		t.Errorf("AccessToken = %q, want %q", tok.AccessToken, "impersonated-token")
+	}
+	if tok.TokenType != "Bearer" {
+		t.Errorf("TokenType = %q, want %q", tok.TokenType, "Bearer")
+	}
+}
+
+func TestImpersonatedTokenSourceErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"error":{"message":"permission denied"}}`))
+	}))
+	defer server.Close()
+
+	ts := &impersonatedTokenSource{
+		base: oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "base-token"}),
+		url:  server.URL,
+	}
+	if _, err := ts.Token(); err == nil {
+		t.Error("Token() returned nil error for a 403 response, want one")
+	}
+}
+
+// fakeSignedJWT builds a syntactically valid, unsigned JWT with the given
+// "exp" claim so jwtExpiry can decode it.
+func fakeSignedJWT(exp int64) string {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`))
+	payload := base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf(`{"exp":%d}`, exp)))
+	return header + "." + payload + ".sig"
+}
+
+func TestImpersonatedJWTTokenSourceToken(t *testing.T) {
+	signedJWT := fakeSignedJWT(4102444800) // 2100-01-01
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Delegates []string `json:"delegates,omitempty"`
+			Payload   string   `json:"payload"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatalf("could not decode request body: %v", err)
+		}
+		if body.Payload != `{"aud":"https://example.com"}` {
+			t.Errorf("payload = %q, want %q", body.Payload, `{"aud":"https://example.com"}`)
+		}
+		fmt.Fprintf(w, `{"keyId":"key-1","signedJwt":%q}`, signedJWT)
+	}))
+	defer server.Close()
+
+	ts := &impersonatedJWTTokenSource{
+		base:    oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "base-token"}),
+		url:     server.URL,
+		payload: `{"aud":"https://example.com"}`,
+	}
+	tok, err := ts.Token()
+	if err != nil {
+		t.Fatalf("Token() returned error: %v", err)
+	}
+	if tok.AccessToken != signedJWT {
+		t.Errorf("AccessToken = %q, want %q", tok.AccessToken, signedJWT)
+	}
+	if tok.Expiry.Unix() != 4102444800 {
+		t.Errorf("Expiry = %v, want unix 4102444800", tok.Expiry)
+	}
+}
+
+func TestJwtExpiryMissingClaim(t *testing.T) {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`))
+	payload := base64.RawURLEncoding.EncodeToString([]byte(`{}`))
+	if _, err := jwtExpiry(header + "." + payload + ".sig"); err == nil {
+		t.Error("jwtExpiry() returned nil error for a JWT with no exp claim, want one")
+	}
+}
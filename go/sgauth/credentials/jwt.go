@@ -0,0 +1,109 @@
+//
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package credentials
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/jws"
+
+	"github.com/google/oauth2l/go/sgauth/internal"
+)
+
+// JWTAccessTokenSourceFromJSON creates a self-signed JWT that can be used
+// as a bearer token in place of an OAuth2 access token, when the target
+// service accepts JWTs signed by the given service account directly. This
+// avoids a network round trip to the token endpoint entirely.
+func JWTAccessTokenSourceFromJSON(jsonKey []byte, audience string) (internal.TokenSource, error) {
+	var f File
+	if err := json.Unmarshal(jsonKey, &f); err != nil {
+		return nil, fmt.Errorf("sgauth/credentials: could not parse JSON key: %v", err)
+	}
+	if f.Type != serviceAccountKey {
+		return nil, fmt.Errorf("sgauth/credentials: self-signed JWTs require a %q key, got %q", serviceAccountKey, f.Type)
+	}
+	pk, err := parsePrivateKey([]byte(f.PrivateKey))
+	if err != nil {
+		return nil, fmt.Errorf("sgauth/credentials: could not parse private key: %v", err)
+	}
+	ts := &jwtAccessTokenSource{
+		email:    f.ClientEmail,
+		audience: audience,
+		keyID:    f.PrivateKeyID,
+		pk:       pk,
+	}
+	tok, err := ts.Token()
+	if err != nil {
+		return nil, err
+	}
+	return oauth2.ReuseTokenSource(tok, ts), nil
+}
+
+type jwtAccessTokenSource struct {
+	email, audience string
+	keyID           string
+	pk              *rsa.PrivateKey
+}
+
+func (ts *jwtAccessTokenSource) Token() (*oauth2.Token, error) {
+	iat := time.Now()
+	exp := iat.Add(time.Hour)
+	cs := &jws.ClaimSet{
+		Iss: ts.email,
+		Sub: ts.email,
+		Aud: ts.audience,
+		Iat: iat.Unix(),
+		Exp: exp.Unix(),
+	}
+	hdr := &jws.Header{
+		Algorithm: "RS256",
+		Typ:       "JWT",
+		KeyID:     ts.keyID,
+	}
+	msg, err := jws.Encode(hdr, cs, ts.pk)
+	if err != nil {
+		return nil, fmt.Errorf("sgauth/credentials: could not encode JWT: %v", err)
+	}
+	return &oauth2.Token{AccessToken: msg, TokenType: "Bearer", Expiry: exp}, nil
+}
+
+// parsePrivateKey parses a PEM encoded PKCS#1 or PKCS#8 private key, as
+// found in the private_key field of a service account JSON key.
+func parsePrivateKey(key []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(key)
+	if block == nil {
+		return nil, errors.New("not a PEM encoded key")
+	}
+	parsedKey, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		parsedKey8, err8 := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err8 != nil {
+			return nil, fmt.Errorf("could not parse key as PKCS1 or PKCS8: %v; %v", err, err8)
+		}
+		var ok bool
+		parsedKey, ok = parsedKey8.(*rsa.PrivateKey)
+		if !ok {
+			return nil, errors.New("private key is not an RSA key")
+		}
+	}
+	return parsedKey, nil
+}
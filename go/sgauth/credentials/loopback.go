@@ -0,0 +1,99 @@
+//
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package credentials
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"runtime"
+)
+
+// LoopbackHandler is an AuthorizationHandler that runs a short-lived HTTP
+// server on 127.0.0.1, opens the authorization URL in the user's browser
+// with that server as the redirect URI, and captures the resulting
+// authorization code from the callback request.
+type LoopbackHandler struct {
+	// OpenBrowser opens url in the user's default browser. Defaults to
+	// openBrowser, which shells out to the platform's "open" equivalent.
+	OpenBrowser func(url string) error
+}
+
+type loopbackResult struct {
+	code, state string
+	err         error
+}
+
+// Authorize implements AuthorizationHandler.
+func (h *LoopbackHandler) Authorize(authCodeURL, state, codeVerifier string) (string, string, string, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", "", "", fmt.Errorf("sgauth/credentials: could not start loopback listener: %v", err)
+	}
+	defer listener.Close()
+
+	redirectURI := fmt.Sprintf("http://127.0.0.1:%d", listener.Addr().(*net.TCPAddr).Port)
+
+	u, err := url.Parse(authCodeURL)
+	if err != nil {
+		return "", "", "", err
+	}
+	q := u.Query()
+	q.Set("redirect_uri", redirectURI)
+	u.RawQuery = q.Encode()
+
+	resultCh := make(chan loopbackResult, 1)
+	srv := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			q := r.URL.Query()
+			if msg := q.Get("error"); msg != "" {
+				resultCh <- loopbackResult{err: fmt.Errorf("authorization server returned error: %s", msg)}
+			} else {
+				resultCh <- loopbackResult{code: q.Get("code"), state: q.Get("state")}
+			}
+			fmt.Fprint(w, "Authentication complete. You may close this window and return to the application.")
+		}),
+	}
+	go srv.Serve(listener)
+	defer srv.Close()
+
+	open := h.OpenBrowser
+	if open == nil {
+		open = openBrowser
+	}
+	if err := open(u.String()); err != nil {
+		return "", "", "", fmt.Errorf("sgauth/credentials: could not open browser: %v", err)
+	}
+
+	res := <-resultCh
+	if res.err != nil {
+		return "", "", "", res.err
+	}
+	return res.code, res.state, redirectURI, nil
+}
+
+// openBrowser opens url using the current platform's default handler.
+func openBrowser(url string) error {
+	switch runtime.GOOS {
+	case "darwin":
+		return exec.Command("open", url).Start()
+	case "windows":
+		return exec.Command("rundll32", "url.dll,FileProtocolHandler", url).Start()
+	default:
+		return exec.Command("xdg-open", url).Start()
+	}
+}
@@ -0,0 +1,39 @@
+//
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package credentials
+
+import (
+	"context"
+
+	"golang.org/x/oauth2/jwt"
+
+	"github.com/google/oauth2l/go/sgauth/internal"
+)
+
+const defaultTokenURL = "https://oauth2.googleapis.com/token"
+
+func (f *File) serviceAccountTokenSource(ctx context.Context, scopes []string) (internal.TokenSource, error) {
+	cfg := &jwt.Config{
+		Email:      f.ClientEmail,
+		PrivateKey: []byte(f.PrivateKey),
+		PrivateKeyID: f.PrivateKeyID,
+		Scopes:     scopes,
+		TokenURL:   f.TokenURL,
+	}
+	if cfg.TokenURL == "" {
+		cfg.TokenURL = defaultTokenURL
+	}
+	return cfg.TokenSource(ctx), nil
+}
@@ -0,0 +1,82 @@
+//
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package credentials
+
+import (
+	"context"
+	"fmt"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+
+	"github.com/google/oauth2l/go/sgauth/internal"
+)
+
+// userCredentialsTokenSource returns a TokenSource for an "authorized_user"
+// credentials file. If the file already carries a refresh token (the usual
+// case for gcloud-generated application_default_credentials.json), it is
+// used directly. Otherwise f is treated as an installed-app client
+// descriptor and a PKCE-protected 3-legged OAuth flow is run through
+// handler to obtain one; no client secret is required for this flow, so
+// public/installed-app clients work without one.
+func (f *File) userCredentialsTokenSource(ctx context.Context, scopes []string, handler AuthorizationHandler, state string) (internal.TokenSource, error) {
+	cfg := &oauth2.Config{
+		ClientID:     f.ClientID,
+		ClientSecret: f.ClientSecret,
+		Scopes:       scopes,
+		Endpoint:     google.Endpoint,
+	}
+
+	if f.RefreshToken != "" {
+		return cfg.TokenSource(ctx, &oauth2.Token{RefreshToken: f.RefreshToken}), nil
+	}
+
+	if handler == nil {
+		return nil, fmt.Errorf("sgauth/credentials: credentials have no refresh_token and no AuthorizationHandler was provided to obtain one")
+	}
+
+	verifier, err := newCodeVerifier()
+	if err != nil {
+		return nil, fmt.Errorf("sgauth/credentials: could not generate PKCE code_verifier: %v", err)
+	}
+	challenge := codeChallengeS256(verifier)
+
+	cfg.RedirectURL = "urn:ietf:wg:oauth:2.0:oob"
+	authURL := cfg.AuthCodeURL(state, oauth2.AccessTypeOffline,
+		oauth2.SetAuthURLParam("code_challenge", challenge),
+		oauth2.SetAuthURLParam("code_challenge_method", "S256"))
+
+	code, receivedState, redirectURI, err := handler.Authorize(authURL, state, verifier)
+	if err != nil {
+		return nil, fmt.Errorf("sgauth/credentials: authorization flow failed: %v", err)
+	}
+	if receivedState != state {
+		return nil, fmt.Errorf("sgauth/credentials: state mismatch in authorization response: got %q, want %q", receivedState, state)
+	}
+
+	exchangeOpts := []oauth2.AuthCodeOption{oauth2.SetAuthURLParam("code_verifier", verifier)}
+	if redirectURI != "" {
+		// The handler sent the user to a redirect_uri other than
+		// cfg.RedirectURL (e.g. LoopbackHandler's local server); the
+		// exchange must use the same one or the token endpoint rejects
+		// it with redirect_uri_mismatch.
+		exchangeOpts = append(exchangeOpts, oauth2.SetAuthURLParam("redirect_uri", redirectURI))
+	}
+	tok, err := cfg.Exchange(ctx, code, exchangeOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("sgauth/credentials: could not exchange authorization code: %v", err)
+	}
+	return cfg.TokenSource(ctx, tok), nil
+}
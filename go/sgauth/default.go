@@ -80,14 +80,28 @@ func NewGrpcJWT(ctx context.Context, aud string) (gRPCCredentials.PerRPCCredenti
 }
 
 func findJSONCredentials(ctx context.Context, settings *Settings) (*credentials.Credentials, error) {
+	var creds *credentials.Credentials
+	var err error
 	if settings.CredentialsJSON != "" {
-		return credentialsFromJSON(ctx, []byte(settings.CredentialsJSON),
+		creds, err = credentialsFromJSON(ctx, []byte(settings.CredentialsJSON),
 			strings.Split(settings.Scope, " "), settings.OAuthFlowHandler, settings.State)
-
+	} else if settings.CredentialsFile != "" {
+		creds, err = readCredentialsFile(ctx, settings.CredentialsFile, settings)
 	} else {
-		return applicationDefaultCredentials(ctx, settings)
-
+		creds, err = applicationDefaultCredentials(ctx, settings)
+	}
+	if err != nil {
+		return nil, err
 	}
+	if settings.ImpersonateServiceAccount != "" {
+		ts, err := credentials.ImpersonatedTokenSource(ctx, creds.TokenSource, settings.ImpersonateServiceAccount,
+			settings.ImpersonateDelegates, strings.Split(settings.Scope, " "), 0)
+		if err != nil {
+			return nil, fmt.Errorf("sgauth: could not impersonate %v: %v", settings.ImpersonateServiceAccount, err)
+		}
+		creds.TokenSource = ts
+	}
+	return creds, nil
 }
 
 func applicationDefaultCredentials(ctx context.Context, settings *Settings) (*credentials.Credentials, error) {
@@ -107,7 +121,12 @@ func applicationDefaultCredentials(ctx context.Context, settings *Settings) (*cr
 		return nil, fmt.Errorf("google: error getting credentials using well-known file (%v): %v", filename, err)
 	}
 
-	// Third, if we're on Google App Engine use those credentials.
+	// Third, try the active gcloud CLI session.
+	if creds, err := gcloudSDKCredentials(ctx, settings); err == nil {
+		return creds, nil
+	}
+
+	// Fourth, if we're on Google App Engine use those credentials.
 	if appengineTokenFunc != nil && !appengineFlex {
 		return &credentials.Credentials{
 			ProjectID:   appengineAppIDFunc(ctx),
@@ -115,7 +134,7 @@ func applicationDefaultCredentials(ctx context.Context, settings *Settings) (*cr
 		}, nil
 	}
 
-	// Fourth, if we're on Google Compute Engine use the metadata server.
+	// Fifth, if we're on Google Compute Engine use the metadata server.
 	if metadata.OnGCE() {
 		id, _ := metadata.ProjectID()
 		return &credentials.Credentials{
@@ -139,7 +158,7 @@ func readCredentialsFile(ctx context.Context, filename string, settings *Setting
 }
 
 func credentialsFromJSON(ctx context.Context, jsonData []byte, scopes []string,
-	handler func(string)(string, error), state string) (*credentials.Credentials, error) {
+	handler credentials.AuthorizationHandler, state string) (*credentials.Credentials, error) {
 	var f credentials.File
 	if err := json.Unmarshal(jsonData, &f); err != nil {
 		return nil, err
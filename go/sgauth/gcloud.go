@@ -0,0 +1,188 @@
+//
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package sgauth
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/google/oauth2l/go/sgauth/credentials"
+)
+
+// gcloudSDKCredentials discovers the refresh token behind the active
+// `gcloud auth login` session, so oauth2l users don't have to export
+// GOOGLE_APPLICATION_CREDENTIALS just to reuse it.
+func gcloudSDKCredentials(ctx context.Context, settings *Settings) (*credentials.Credentials, error) {
+	dir := gcloudConfigDir()
+
+	configName, err := activeGcloudConfigName(dir)
+	if err != nil {
+		return nil, err
+	}
+	account, err := activeGcloudAccount(dir, configName)
+	if err != nil {
+		return nil, err
+	}
+	f, err := gcloudUserCredentialsFile(dir, account)
+	if err != nil {
+		return nil, err
+	}
+	ts, err := f.TokenSource(ctx, strings.Split(settings.Scope, " "), settings.OAuthFlowHandler, settings.State)
+	if err != nil {
+		return nil, err
+	}
+	return &credentials.Credentials{TokenSource: ts, JSON: nil}, nil
+}
+
+// gcloudConfigDir returns the directory gcloud stores its configuration
+// and credentials in, honoring CLOUDSDK_CONFIG.
+func gcloudConfigDir() string {
+	if v := os.Getenv("CLOUDSDK_CONFIG"); v != "" {
+		return v
+	}
+	if runtime.GOOS == "windows" {
+		return filepath.Join(os.Getenv("APPDATA"), "gcloud")
+	}
+	return filepath.Join(guessUnixHomeDir(), ".config", "gcloud")
+}
+
+// activeGcloudConfigName reads the name of the active named configuration,
+// e.g. "default".
+func activeGcloudConfigName(dir string) (string, error) {
+	b, err := ioutil.ReadFile(filepath.Join(dir, "active_config"))
+	if err != nil {
+		return "", err
+	}
+	name := strings.TrimSpace(string(b))
+	if name == "" {
+		return "", fmt.Errorf("sgauth: empty gcloud active_config")
+	}
+	return name, nil
+}
+
+// activeGcloudAccount reads the "account" key out of the [core] section of
+// the named configuration's ini file.
+func activeGcloudAccount(dir, configName string) (string, error) {
+	path := filepath.Join(dir, "configurations", "config_"+configName)
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	section := ""
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.ToLower(strings.Trim(line, "[]"))
+			continue
+		}
+		if section != "core" {
+			continue
+		}
+		kv := strings.SplitN(line, "=", 2)
+		if len(kv) == 2 && strings.TrimSpace(kv[0]) == "account" {
+			return strings.TrimSpace(kv[1]), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("sgauth: no account set in gcloud configuration %q", configName)
+}
+
+// gcloudCredential is the shape gcloud stores per-account OAuth2 user
+// credentials in, both in credentials.db and in the legacy JSON store.
+type gcloudCredential struct {
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	RefreshToken string `json:"refresh_token"`
+	TokenURI     string `json:"token_uri"`
+}
+
+// gcloudUserCredentialsFile locates account's stored OAuth2 credentials,
+// preferring the current credentials.db SQLite store and falling back to
+// the legacy "credentials" JSON file used by older gcloud releases.
+func gcloudUserCredentialsFile(dir, account string) (*credentials.File, error) {
+	cred, err := gcloudCredentialFromDB(filepath.Join(dir, "credentials.db"), account)
+	if err != nil {
+		cred, err = gcloudCredentialFromLegacyFile(filepath.Join(dir, "credentials"), account)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("sgauth: could not find gcloud credentials for %v: %v", account, err)
+	}
+	return &credentials.File{
+		Type:         "authorized_user",
+		ClientID:     cred.ClientID,
+		ClientSecret: cred.ClientSecret,
+		RefreshToken: cred.RefreshToken,
+	}, nil
+}
+
+func gcloudCredentialFromDB(dbPath, account string) (*gcloudCredential, error) {
+	db, err := sql.Open("sqlite", dbPath+"?mode=ro")
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	var value string
+	row := db.QueryRow(`SELECT value FROM credentials WHERE account_id = ?`, account)
+	if err := row.Scan(&value); err != nil {
+		return nil, err
+	}
+	var cred gcloudCredential
+	if err := json.Unmarshal([]byte(value), &cred); err != nil {
+		return nil, err
+	}
+	return &cred, nil
+}
+
+// legacy gcloud "credentials" file format: a JSON object with a "data"
+// array of {"key": {"account": ...}, "credential": {...}} entries.
+func gcloudCredentialFromLegacyFile(path, account string) (*gcloudCredential, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var store struct {
+		Data []struct {
+			Key struct {
+				Account string `json:"account"`
+			} `json:"key"`
+			Credential gcloudCredential `json:"credential"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(b, &store); err != nil {
+		return nil, err
+	}
+	for _, entry := range store.Data {
+		if entry.Key.Account == account {
+			return &entry.Credential, nil
+		}
+	}
+	return nil, fmt.Errorf("no stored credentials for account %q", account)
+}
@@ -0,0 +1,62 @@
+//
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package sgauth
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeGcloudConfig(t *testing.T, dir, configName, content string) {
+	t.Helper()
+	configsDir := filepath.Join(dir, "configurations")
+	if err := os.MkdirAll(configsDir, 0700); err != nil {
+		t.Fatalf("MkdirAll: %v", err)
+	}
+	path := filepath.Join(configsDir, "config_"+configName)
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+}
+
+func TestActiveGcloudAccount(t *testing.T) {
+	dir := t.TempDir()
+	writeGcloudConfig(t, dir, "default", "[core]\naccount = user@example.com\ndisable_usage_reporting = True\n")
+
+	account, err := activeGcloudAccount(dir, "default")
+	if err != nil {
+		t.Fatalf("activeGcloudAccount() returned error: %v", err)
+	}
+	if want := "user@example.com"; account != want {
+		t.Errorf("activeGcloudAccount() = %q, want %q", account, want)
+	}
+}
+
+func TestActiveGcloudAccountWrongSection(t *testing.T) {
+	dir := t.TempDir()
+	writeGcloudConfig(t, dir, "default", "[compute]\naccount = user@example.com\n[core]\nproject = my-project\n")
+
+	if _, err := activeGcloudAccount(dir, "default"); err == nil {
+		t.Error("activeGcloudAccount() returned nil error for a config with no account in [core], want one")
+	}
+}
+
+func TestActiveGcloudAccountMissingFile(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := activeGcloudAccount(dir, "default"); err == nil {
+		t.Error("activeGcloudAccount() returned nil error for a missing config file, want one")
+	}
+}
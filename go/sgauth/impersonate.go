@@ -0,0 +1,32 @@
+//
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package sgauth
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/oauth2l/go/sgauth/credentials"
+	"github.com/google/oauth2l/go/sgauth/internal"
+)
+
+// NewImpersonatedTokenSource wraps base so that, instead of returning
+// base's own tokens, it returns access tokens for the service account
+// target, following the chain of delegates if any. base must already be
+// authorized to impersonate target (directly, or transitively through
+// delegates). See credentials.ImpersonatedTokenSource for details.
+func NewImpersonatedTokenSource(ctx context.Context, base internal.TokenSource, target string, delegates []string, scopes []string, lifetime time.Duration) (internal.TokenSource, error) {
+	return credentials.ImpersonatedTokenSource(ctx, base, target, delegates, scopes, lifetime)
+}
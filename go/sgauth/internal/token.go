@@ -0,0 +1,54 @@
+//
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package internal holds code shared across the exported sgauth packages
+// that should not itself be part of the public API.
+package internal
+
+import (
+	"context"
+
+	"golang.org/x/oauth2"
+)
+
+// TokenSource supplies access tokens. It is structurally identical to
+// oauth2.TokenSource so that any oauth2.TokenSource (including the ones
+// returned by oauth2.ReuseTokenSource) can be used wherever a
+// internal.TokenSource is expected.
+type TokenSource interface {
+	Token() (*oauth2.Token, error)
+}
+
+// GrpcTokenSource adapts a TokenSource to the
+// credentials.PerRPCCredentials interface used by grpc-go.
+type GrpcTokenSource struct {
+	TokenSource
+}
+
+// GetRequestMetadata implements credentials.PerRPCCredentials.
+func (ts GrpcTokenSource) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	token, err := ts.Token()
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{
+		"authorization": token.Type() + " " + token.AccessToken,
+	}, nil
+}
+
+// RequireTransportSecurity implements credentials.PerRPCCredentials.
+func (ts GrpcTokenSource) RequireTransportSecurity() bool {
+	return true
+}
@@ -0,0 +1,63 @@
+//
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package sgauth
+
+import "github.com/google/oauth2l/go/sgauth/credentials"
+
+// Settings holds the parameters that steer how sgauth discovers and mints
+// credentials.
+type Settings struct {
+	// Scope is a space separated list of OAuth2 scopes to request.
+	Scope string
+
+	// Audience is used in place of Scope when minting a self-signed or
+	// service-account JWT for a single target service.
+	Audience string
+
+	// CredentialsJSON, if set, is used instead of discovering Application
+	// Default Credentials.
+	CredentialsJSON string
+
+	// CredentialsFile, if set, is the path to a JSON credentials file to
+	// use instead of discovering Application Default Credentials.
+	CredentialsFile string
+
+	// State is the anti-CSRF token sent to and expected back from the
+	// 3-legged OAuth authorization endpoint.
+	State string
+
+	// OAuthFlowHandler drives the user-facing half of a 3-legged OAuth
+	// flow when no refresh token is already on hand; see
+	// credentials.LoopbackHandler and credentials.ConsoleHandler for
+	// ready-made implementations.
+	OAuthFlowHandler credentials.AuthorizationHandler
+
+	// ImpersonateServiceAccount, if set, is the email of a service account
+	// to impersonate. The credentials discovered from CredentialsJSON /
+	// CredentialsFile / Application Default Credentials are used as the
+	// base identity, which must have the "Service Account Token Creator"
+	// role on the target account.
+	ImpersonateServiceAccount string
+
+	// ImpersonateDelegates is an optional chain of intermediate service
+	// accounts, each of which must have been granted Token Creator on the
+	// next, culminating in ImpersonateServiceAccount.
+	ImpersonateDelegates []string
+
+	// QuotaProject, if set, is billed for requests made with a client from
+	// NewClient instead of the project tied to the credentials, sent as
+	// the X-Goog-User-Project header. DefaultClient never sets it.
+	QuotaProject string
+}